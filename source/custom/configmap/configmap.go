@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package configmap watches a set of trusted ConfigMaps for custom rule
+// documents and feeds updates back to source/custom without requiring an
+// nfd-worker restart.
+package configmap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+// Config controls how the custom rule ConfigMap watcher connects to the
+// cluster and which ConfigMaps it is allowed to pull rules from.
+type Config struct {
+	// Kubeconfig is the path to a kubeconfig file. When empty, the
+	// in-cluster config is used, falling back to $HOME/.kube/config.
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// ResyncPeriod controls how often the informer resyncs its local cache.
+	ResyncPeriod time.Duration `json:"resyncPeriod,omitempty"`
+	// LabelSelector restricts the watched ConfigMaps to those matching the
+	// given label selector. Empty means all ConfigMaps in TrustedNamespaces.
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// TrustedNamespaces is the allowlist of namespaces that custom rule
+	// ConfigMaps may be read from. ConfigMaps outside of this list are
+	// ignored so that cluster-wide rules can only originate from a
+	// curated set of namespaces.
+	TrustedNamespaces []string `json:"trustedNamespaces,omitempty"`
+}
+
+// newDefaultConfig returns a new Config with pre-populated defaults.
+func newDefaultConfig() *Config {
+	return &Config{
+		ResyncPeriod: time.Hour,
+	}
+}
+
+// UpdateFunc is called with the merged set of rule documents, keyed by
+// "<namespace>/<name>/<data key>", whenever a watched ConfigMap changes.
+type UpdateFunc func(docs map[string][]byte)
+
+// Watcher watches a set of trusted ConfigMaps for custom rule documents and
+// invokes an UpdateFunc whenever the merged rule set changes.
+type Watcher struct {
+	cfg      Config
+	client   kubernetes.Interface
+	onUpdate UpdateFunc
+
+	m    sync.Mutex
+	docs map[string][]byte
+}
+
+// NewWatcher creates a Watcher for the given configuration. If cfg is nil,
+// newDefaultConfig() is used.
+func NewWatcher(cfg *Config, onUpdate UpdateFunc) (*Watcher, error) {
+	if cfg == nil {
+		cfg = newDefaultConfig()
+	}
+	restConfig, err := buildRestConfig(cfg.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kube client config: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kube client: %w", err)
+	}
+	return &Watcher{
+		cfg:      *cfg,
+		client:   client,
+		onUpdate: onUpdate,
+		docs:     make(map[string][]byte),
+	}, nil
+}
+
+func buildRestConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig != "" {
+		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	if cfg, err := rest.InClusterConfig(); err == nil {
+		return cfg, nil
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if cfg, err := clientcmd.BuildConfigFromFlags("", filepath.Join(home, ".kube", "config")); err == nil {
+			return cfg, nil
+		}
+	}
+	return nil, fmt.Errorf("no in-cluster config and no usable kubeconfig found")
+}
+
+// Run starts the ConfigMap informer and blocks until stopCh is closed. The
+// informer is scoped to w.cfg.TrustedNamespaces; if empty, all namespaces
+// are watched (relying on LabelSelector alone to curate the rule set).
+func (w *Watcher) Run(stopCh <-chan struct{}) error {
+	namespaces := w.cfg.TrustedNamespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{corev1.NamespaceAll}
+	}
+
+	factories := make([]informers.SharedInformerFactory, 0, len(namespaces))
+	for _, ns := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(w.client, w.cfg.ResyncPeriod,
+			informers.WithNamespace(ns),
+			informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+				opts.LabelSelector = w.cfg.LabelSelector
+			}),
+		)
+		informer := factory.Core().V1().ConfigMaps().Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { w.handle(obj) },
+			UpdateFunc: func(_, obj interface{}) { w.handle(obj) },
+			DeleteFunc: func(obj interface{}) { w.handleDelete(obj) },
+		})
+		factories = append(factories, factory)
+	}
+
+	for _, factory := range factories {
+		factory.Start(stopCh)
+		factory.WaitForCacheSync(stopCh)
+	}
+
+	klog.Infof("custom rule ConfigMap watcher started, trusted namespaces: %v", namespaces)
+	<-stopCh
+	return nil
+}
+
+func (w *Watcher) handle(obj interface{}) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	if !w.isTrusted(cm) {
+		return
+	}
+
+	prefix := cm.Namespace + "/" + cm.Name + "/"
+
+	w.m.Lock()
+	// Recompute this ConfigMap's contribution from scratch so that a data
+	// key removed on update doesn't leave its stale rules behind.
+	for key := range w.docs {
+		if strings.HasPrefix(key, prefix) {
+			delete(w.docs, key)
+		}
+	}
+	for key, data := range cm.Data {
+		w.docs[prefix+key] = []byte(data)
+	}
+	docs := w.snapshotLocked()
+	w.m.Unlock()
+
+	w.onUpdate(docs)
+}
+
+func (w *Watcher) handleDelete(obj interface{}) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			cm, _ = tombstone.Obj.(*corev1.ConfigMap)
+		}
+		if cm == nil {
+			return
+		}
+	}
+
+	w.m.Lock()
+	for key := range cm.Data {
+		delete(w.docs, cm.Namespace+"/"+cm.Name+"/"+key)
+	}
+	docs := w.snapshotLocked()
+	w.m.Unlock()
+
+	w.onUpdate(docs)
+}
+
+// snapshotLocked returns a copy of w.docs. Caller must hold w.m.
+func (w *Watcher) snapshotLocked() map[string][]byte {
+	docs := make(map[string][]byte, len(w.docs))
+	for k, v := range w.docs {
+		docs[k] = v
+	}
+	return docs
+}
+
+func (w *Watcher) isTrusted(cm *corev1.ConfigMap) bool {
+	if len(w.cfg.TrustedNamespaces) == 0 {
+		return true
+	}
+	for _, ns := range w.cfg.TrustedNamespaces {
+		if ns == cm.Namespace {
+			return true
+		}
+	}
+	return false
+}
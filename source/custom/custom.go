@@ -17,12 +17,17 @@ limitations under the License.
 package custom
 
 import (
+	"bytes"
 	"reflect"
+	"sync"
+	"text/template"
 
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
 
 	"openshift/node-feature-discovery/pkg/utils"
 	"openshift/node-feature-discovery/source"
+	"openshift/node-feature-discovery/source/custom/configmap"
 	"openshift/node-feature-discovery/source/custom/rules"
 )
 
@@ -34,12 +39,79 @@ type MatchRule struct {
 	CpuID      *rules.CpuIDRule      `json:"cpuId,omitempty"`
 	Kconfig    *rules.KconfigRule    `json:"kConfig,omitempty"`
 	Nodename   *rules.NodenameRule   `json:"nodename,omitempty"`
+	Sysfs      *rules.SysfsRule      `json:"sysfs,omitempty"`
+	DMI        *rules.DMIRule        `json:"dmi,omitempty"`
+	OSRelease  *rules.OSReleaseRule  `json:"osRelease,omitempty"`
+}
+
+// MatchExpression composes atomic MatchRule conditions with allOf, anyOf
+// and not operators, evaluated recursively. Exactly one of Rule, AllOf,
+// AnyOf or Not is expected to be set on any given node.
+type MatchExpression struct {
+	Rule  *MatchRule        `json:"rule,omitempty"`
+	AllOf []MatchExpression `json:"allOf,omitempty"`
+	AnyOf []MatchExpression `json:"anyOf,omitempty"`
+	Not   *MatchExpression  `json:"not,omitempty"`
+}
+
+// Match implements the rules.Rule interface, walking the expression tree
+// and short-circuiting as soon as the result is determined. Captured
+// values are merged across all matched sub-expressions; a Not node never
+// captures anything since it only proves the absence of a match.
+func (e *MatchExpression) Match() (bool, map[string]string, error) {
+	switch {
+	case e.Rule != nil:
+		return e.Rule.match()
+	case len(e.AllOf) > 0:
+		captured := map[string]string{}
+		for i := range e.AllOf {
+			match, sub, err := e.AllOf[i].Match()
+			if err != nil {
+				return false, nil, err
+			}
+			if !match {
+				return false, nil, nil
+			}
+			mergeCaptured(captured, sub)
+		}
+		return true, captured, nil
+	case len(e.AnyOf) > 0:
+		for i := range e.AnyOf {
+			match, captured, err := e.AnyOf[i].Match()
+			if err != nil {
+				return false, nil, err
+			}
+			if match {
+				return true, captured, nil
+			}
+		}
+		return false, nil, nil
+	case e.Not != nil:
+		match, _, err := e.Not.Match()
+		if err != nil {
+			return false, nil, err
+		}
+		return !match, nil, nil
+	}
+	return false, nil, nil
+}
+
+// mergeCaptured copies src's entries into dst.
+func mergeCaptured(dst, src map[string]string) {
+	for k, v := range src {
+		dst[k] = v
+	}
 }
 
 type FeatureSpec struct {
 	Name    string      `json:"name"`
 	Value   *string     `json:"value,omitempty"`
 	MatchOn []MatchRule `json:"matchOn"`
+	// MatchExpressions allows composing the atomic rules in MatchOn with
+	// allOf/anyOf/not operators. It is parsed and evaluated alongside
+	// MatchOn for backward compatibility; a feature matches if either
+	// MatchOn or MatchExpressions matches.
+	MatchExpressions []MatchExpression `json:"matchExpressions,omitempty"`
 }
 
 type config []FeatureSpec
@@ -52,10 +124,88 @@ func newDefaultConfig() *config {
 // Implements FeatureSource Interface
 type Source struct {
 	config *config
+
+	// cmMu guards cmConfig and cmUpdated, the custom rules sourced from
+	// watched ConfigMaps (see SetConfigMapRules) and the channel used to
+	// signal a change in them.
+	cmMu      sync.RWMutex
+	cmConfig  *config
+	cmUpdated chan struct{}
+}
+
+// InitConfigMapSource starts watching the ConfigMaps described by cfg and
+// feeds any custom rules they contain into s, the "custom" feature
+// source's own instance, mirroring how SetConfig feeds in the static
+// config. It returns immediately; the watch runs until stopCh is closed.
+func (s *Source) InitConfigMapSource(cfg configmap.Config, stopCh <-chan struct{}) error {
+	w, err := configmap.NewWatcher(&cfg, func(docs map[string][]byte) { s.onConfigMapUpdate(docs) })
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := w.Run(stopCh); err != nil {
+			klog.Errorf("custom rule ConfigMap watcher stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// onConfigMapUpdate parses each watched ConfigMap data key as a YAML
+// []FeatureSpec document and pushes the merged rule set into s via
+// SetConfigMapRules.
+func (s *Source) onConfigMapUpdate(docs map[string][]byte) {
+	var merged []FeatureSpec
+	for key, data := range docs {
+		var specs []FeatureSpec
+		if err := yaml.Unmarshal(data, &specs); err != nil {
+			klog.Errorf("failed to parse custom rules from ConfigMap key %q: %v", key, err)
+			continue
+		}
+		merged = append(merged, specs...)
+	}
+	s.SetConfigMapRules(merged)
+}
+
+// SetConfigMapRules updates the custom rules sourced from watched
+// ConfigMaps, analogous to how SetConfig updates the static config, and
+// signals ConfigMapUpdated() so the next Discover() picks them up.
+func (s *Source) SetConfigMapRules(specs []FeatureSpec) {
+	cfg := config(specs)
+
+	s.cmMu.Lock()
+	s.cmConfig = &cfg
+	if s.cmUpdated != nil {
+		close(s.cmUpdated)
+	}
+	s.cmUpdated = make(chan struct{})
+	s.cmMu.Unlock()
+}
+
+// getConfigMapFeatureConfig returns the custom rules currently sourced
+// from trusted ConfigMaps.
+func (s *Source) getConfigMapFeatureConfig() []FeatureSpec {
+	s.cmMu.RLock()
+	defer s.cmMu.RUnlock()
+
+	if s.cmConfig == nil {
+		return nil
+	}
+	return *s.cmConfig
+}
+
+// ConfigMapUpdated returns a channel that is closed whenever the merged
+// ConfigMap rule set changes, so callers can trigger a re-Discover().
+func (s *Source) ConfigMapUpdated() <-chan struct{} {
+	s.cmMu.Lock()
+	defer s.cmMu.Unlock()
+	if s.cmUpdated == nil {
+		s.cmUpdated = make(chan struct{})
+	}
+	return s.cmUpdated
 }
 
 // Return name of the feature source
-func (s Source) Name() string { return "custom" }
+func (s *Source) Name() string { return "custom" }
 
 // NewConfig method of the FeatureSource interface
 func (s *Source) NewConfig() source.Config { return newDefaultConfig() }
@@ -74,14 +224,15 @@ func (s *Source) SetConfig(conf source.Config) {
 }
 
 // Discover features
-func (s Source) Discover() (source.Features, error) {
+func (s *Source) Discover() (source.Features, error) {
 	features := source.Features{}
 	allFeatureConfig := append(getStaticFeatureConfig(), *s.config...)
 	allFeatureConfig = append(allFeatureConfig, getDirectoryFeatureConfig()...)
+	allFeatureConfig = append(allFeatureConfig, s.getConfigMapFeatureConfig()...)
 	utils.KlogDump(2, "custom features configuration:", "  ", allFeatureConfig)
 	// Iterate over features
 	for _, customFeature := range allFeatureConfig {
-		featureExist, err := s.discoverFeature(customFeature)
+		featureExist, captured, err := s.discoverFeature(customFeature)
 		if err != nil {
 			klog.Errorf("failed to discover feature: %q: %s", customFeature.Name, err.Error())
 			continue
@@ -89,7 +240,12 @@ func (s Source) Discover() (source.Features, error) {
 		if featureExist {
 			var value interface{} = true
 			if customFeature.Value != nil {
-				value = *customFeature.Value
+				rendered, err := renderValue(*customFeature.Value, captured)
+				if err != nil {
+					klog.Errorf("failed to render value template for feature %q: %s", customFeature.Name, err.Error())
+					continue
+				}
+				value = rendered
 			}
 			features[customFeature.Name] = value
 		}
@@ -97,40 +253,71 @@ func (s Source) Discover() (source.Features, error) {
 	return features, nil
 }
 
-// Process a single feature by Matching on the defined rules.
-// A feature is present if all defined Rules in a MatchRule return a match.
-func (s Source) discoverFeature(feature FeatureSpec) (bool, error) {
-	for _, matchRules := range feature.MatchOn {
+// renderValue expands value as a text/template against the captured
+// values gathered from a feature's matched rules.
+func renderValue(value string, captured map[string]string) (string, error) {
+	tmpl, err := template.New("value").Option("missingkey=zero").Parse(value)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, captured); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// match returns true if all non-nil atomic rules in m match, along with
+// the captured values merged across all of them.
+func (m MatchRule) match() (bool, map[string]string, error) {
+	allRules := []rules.Rule{
+		m.PciID,
+		m.UsbID,
+		m.LoadedKMod,
+		m.CpuID,
+		m.Kconfig,
+		m.Nodename,
+		m.Sysfs,
+		m.DMI,
+		m.OSRelease,
+	}
 
-		allRules := []rules.Rule{
-			matchRules.PciID,
-			matchRules.UsbID,
-			matchRules.LoadedKMod,
-			matchRules.CpuID,
-			matchRules.Kconfig,
-			matchRules.Nodename,
+	captured := map[string]string{}
+	for _, rule := range allRules {
+		if reflect.ValueOf(rule).IsNil() {
+			continue
+		}
+		match, sub, err := rule.Match()
+		if err != nil {
+			return false, nil, err
 		}
+		if !match {
+			return false, nil, nil
+		}
+		mergeCaptured(captured, sub)
+	}
+	return true, captured, nil
+}
 
-		// return true, nil if all rules match
-		matchRules := func(rules []rules.Rule) (bool, error) {
-			for _, rule := range rules {
-				if reflect.ValueOf(rule).IsNil() {
-					continue
-				}
-				if match, err := rule.Match(); err != nil {
-					return false, err
-				} else if !match {
-					return false, nil
-				}
-			}
-			return true, nil
+// Process a single feature by Matching on the defined rules.
+// A feature is present if any MatchOn entry or MatchExpressions entry
+// matches. The captured values of the matching entry are returned so the
+// caller can expand a templated Value against them.
+func (s *Source) discoverFeature(feature FeatureSpec) (bool, map[string]string, error) {
+	for _, matchRules := range feature.MatchOn {
+		if match, captured, err := matchRules.match(); err != nil {
+			return false, nil, err
+		} else if match {
+			return true, captured, nil
 		}
+	}
 
-		if match, err := matchRules(allRules); err != nil {
-			return false, err
+	for i := range feature.MatchExpressions {
+		if match, captured, err := feature.MatchExpressions[i].Match(); err != nil {
+			return false, nil, err
 		} else if match {
-			return true, nil
+			return true, captured, nil
 		}
 	}
-	return false, nil
+	return false, nil, nil
 }
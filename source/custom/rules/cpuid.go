@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+const procCpuinfoPath = "/proc/cpuinfo"
+
+// CpuIDRule matches against the CPU flags/features advertised in
+// /proc/cpuinfo.
+type CpuIDRule []string
+
+// Match implements the Rule interface. It returns true if all the listed
+// CPU flags are present, capturing the matched flags as CpuFlags.
+func (r *CpuIDRule) Match() (bool, map[string]string, error) {
+	flags, err := cpuFlags()
+	if err != nil {
+		return false, nil, err
+	}
+
+	for _, flag := range *r {
+		if !flags[strings.ToLower(flag)] {
+			return false, nil, nil
+		}
+	}
+
+	var captured map[string]string
+	if len(*r) > 0 {
+		captured = map[string]string{"CpuFlags": strings.Join(*r, ",")}
+	}
+	return true, captured, nil
+}
+
+func cpuFlags() (map[string]bool, error) {
+	f, err := os.Open(procCpuinfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	flags := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "flags") && !strings.HasPrefix(line, "Features") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		for _, flag := range strings.Fields(parts[1]) {
+			flags[strings.ToLower(flag)] = true
+		}
+		// All cores share the same flags; the first entry is enough.
+		break
+	}
+	return flags, scanner.Err()
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const dmiIDPath = "/sys/class/dmi/id"
+
+// DMIRule matches DMI/SMBIOS hardware-family attributes exposed under
+// /sys/class/dmi/id, such as product_name, board_vendor or chassis_type.
+type DMIRule struct {
+	ProductName []string `json:"productName,omitempty"`
+	BoardVendor []string `json:"boardVendor,omitempty"`
+	ChassisType []string `json:"chassisType,omitempty"`
+}
+
+// Match implements the Rule interface. On a match it captures the
+// matched attributes as DmiProductName, DmiBoardVendor and DmiChassisType.
+func (r *DMIRule) Match() (bool, map[string]string, error) {
+	if len(r.ProductName) > 0 && !dmiAttrInSlice("product_name", r.ProductName) {
+		return false, nil, nil
+	}
+	if len(r.BoardVendor) > 0 && !dmiAttrInSlice("board_vendor", r.BoardVendor) {
+		return false, nil, nil
+	}
+	if len(r.ChassisType) > 0 && !dmiAttrInSlice("chassis_type", r.ChassisType) {
+		return false, nil, nil
+	}
+
+	captured := map[string]string{
+		"DmiProductName": dmiAttr("product_name"),
+		"DmiBoardVendor": dmiAttr("board_vendor"),
+		"DmiChassisType": dmiAttr("chassis_type"),
+	}
+	return true, captured, nil
+}
+
+func dmiAttr(attr string) string {
+	raw, err := ioutil.ReadFile(filepath.Join(dmiIDPath, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+func dmiAttrInSlice(attr string, values []string) bool {
+	got := dmiAttr(attr)
+	if got == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(got, v) {
+			return true
+		}
+	}
+	return false
+}
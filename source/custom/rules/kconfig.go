@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KconfigRule matches against options set (e.g. "KCONFIG=y" or
+// "KCONFIG=m") in the running kernel's config.
+type KconfigRule []string
+
+// Match implements the Rule interface. It returns true if all the listed
+// kconfig options are set to the given value; an option listed without
+// "=<value>" must be set to "y".
+func (r *KconfigRule) Match() (bool, map[string]string, error) {
+	kconfig, err := parseKconfig()
+	if err != nil {
+		return false, nil, err
+	}
+
+	captured := make(map[string]string, len(*r))
+	for _, opt := range *r {
+		name, want := opt, "y"
+		if idx := strings.Index(opt, "="); idx >= 0 {
+			name, want = opt[:idx], opt[idx+1:]
+		}
+		if kconfig[name] != want {
+			return false, nil, nil
+		}
+		captured[name] = kconfig[name]
+	}
+	return true, captured, nil
+}
+
+func parseKconfig() (map[string]string, error) {
+	for _, path := range []string{"/proc/config.gz", "/boot/config"} {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+
+		var scanner *bufio.Scanner
+		if strings.HasSuffix(path, ".gz") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return nil, err
+			}
+			defer gz.Close()
+			scanner = bufio.NewScanner(gz)
+		} else {
+			scanner = bufio.NewScanner(f)
+		}
+
+		kconfig := make(map[string]string)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) == 2 {
+				kconfig[parts[0]] = parts[1]
+			}
+		}
+		return kconfig, scanner.Err()
+	}
+	return nil, fmt.Errorf("no kernel config found")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	procModulesPath = "/proc/modules"
+	sysModulePath   = "/sys/module"
+)
+
+// LoadedKModRule matches against the set of currently loaded kernel modules.
+type LoadedKModRule []string
+
+// Match implements the Rule interface. It returns true if all the listed
+// modules are currently loaded. On a match it captures the last listed
+// module's version (if exposed under /sys/module) as KModVersion.
+func (r *LoadedKModRule) Match() (bool, map[string]string, error) {
+	loaded, err := loadedKMods()
+	if err != nil {
+		return false, nil, err
+	}
+
+	var version string
+	for _, mod := range *r {
+		if !loaded[mod] {
+			return false, nil, nil
+		}
+		version = kmodVersion(mod)
+	}
+
+	var captured map[string]string
+	if version != "" {
+		captured = map[string]string{"KModVersion": version}
+	}
+	return true, captured, nil
+}
+
+// kmodVersion reads the optional /sys/module/<name>/version file exposed
+// by some kernel modules, returning "" if it isn't present.
+func kmodVersion(mod string) string {
+	raw, err := ioutil.ReadFile(filepath.Join(sysModulePath, mod, "version"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+func loadedKMods() (map[string]bool, error) {
+	f, err := os.Open(procModulesPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mods := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			mods[fields[0]] = true
+		}
+	}
+	return mods, scanner.Err()
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"os"
+	"regexp"
+)
+
+// NodenameRule matches the node's hostname against a list of regular
+// expressions.
+type NodenameRule []string
+
+// Match implements the Rule interface. It returns true if any of the
+// listed regular expressions match the node's hostname, capturing the
+// hostname as Nodename plus any named submatches from the pattern.
+func (r *NodenameRule) Match() (bool, map[string]string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return false, nil, err
+	}
+
+	for _, pattern := range *r {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, nil, err
+		}
+		if m := re.FindStringSubmatch(hostname); m != nil {
+			captured := map[string]string{"Nodename": hostname}
+			for i, name := range re.SubexpNames() {
+				if i != 0 && name != "" {
+					captured[name] = m[i]
+				}
+			}
+			return true, captured, nil
+		}
+	}
+	return false, nil, nil
+}
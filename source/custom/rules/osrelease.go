@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const osReleasePath = "/etc/os-release"
+
+// OSReleaseRule matches the distribution ID and/or VERSION_ID parsed out
+// of /etc/os-release. VersionID supports an optional comparison operator
+// prefix ("<", "<=", ">", ">=", "=="); with no operator, an exact match is
+// required.
+type OSReleaseRule struct {
+	ID        []string `json:"id,omitempty"`
+	VersionID string   `json:"versionId,omitempty"`
+}
+
+// Match implements the Rule interface. On a match it captures the
+// distribution's ID and VERSION_ID as OsID and OsVersionID.
+func (r *OSReleaseRule) Match() (bool, map[string]string, error) {
+	osRelease, err := parseOSRelease()
+	if err != nil {
+		return false, nil, err
+	}
+
+	if len(r.ID) > 0 {
+		match := false
+		for _, id := range r.ID {
+			if strings.EqualFold(osRelease["ID"], id) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false, nil, nil
+		}
+	}
+
+	if r.VersionID != "" {
+		match, err := compareVersion(osRelease["VERSION_ID"], r.VersionID)
+		if err != nil || !match {
+			return false, nil, err
+		}
+	}
+
+	captured := map[string]string{
+		"OsID":        osRelease["ID"],
+		"OsVersionID": osRelease["VERSION_ID"],
+	}
+	return true, captured, nil
+}
+
+func parseOSRelease() (map[string]string, error) {
+	f, err := os.Open(osReleasePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vals := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		vals[parts[0]] = strings.Trim(parts[1], `"`)
+	}
+	return vals, scanner.Err()
+}
+
+// compareVersion evaluates have against an optionally-operator-prefixed
+// want, comparing dot-separated numeric components left to right. A have
+// that is empty or non-numeric (rolling-release distros often omit or
+// don't set VERSION_ID) is treated as a non-match rather than an error;
+// an invalid want is a rule configuration error.
+func compareVersion(have, want string) (bool, error) {
+	op := "=="
+	for _, candidate := range []string{">=", "<=", ">", "<", "=="} {
+		if strings.HasPrefix(want, candidate) {
+			op = candidate
+			want = strings.TrimPrefix(want, candidate)
+			break
+		}
+	}
+	want = strings.TrimSpace(want)
+
+	haveParts, err := parseVersion(have)
+	if err != nil {
+		return false, nil
+	}
+	wantParts, err := parseVersion(want)
+	if err != nil {
+		return false, fmt.Errorf("invalid versionId %q: %w", want, err)
+	}
+
+	cmp := compareVersionParts(haveParts, wantParts)
+
+	switch op {
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	default:
+		return cmp == 0, nil
+	}
+}
+
+// parseVersion splits a dot-separated version string into its numeric
+// components, failing if it's empty or any component isn't a number.
+func parseVersion(v string) ([]int, error) {
+	if v == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version component %q: %w", p, err)
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+func compareVersionParts(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var av, bv int
+		if i < len(a) {
+			av = a[i]
+		}
+		if i < len(b) {
+			bv = b[i]
+		}
+		if av != bv {
+			if av < bv {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const pciDevicesPath = "/sys/bus/pci/devices"
+
+// PciIDRule matches PCI devices by class, vendor and/or device id.
+type PciIDRule struct {
+	Class  []string `json:"class,omitempty"`
+	Vendor []string `json:"vendor,omitempty"`
+	Device []string `json:"device,omitempty"`
+}
+
+// Match implements the Rule interface. On a match it captures the
+// matched device's class, vendor and device id as PciClass, PciVendor and
+// PciDevice.
+func (r *PciIDRule) Match() (bool, map[string]string, error) {
+	devDirs, err := ioutil.ReadDir(pciDevicesPath)
+	if err != nil {
+		return false, nil, err
+	}
+
+	for _, devDir := range devDirs {
+		devPath := filepath.Join(pciDevicesPath, devDir.Name())
+
+		if len(r.Class) > 0 && !attrInSlice(devPath, "class", r.Class) {
+			continue
+		}
+		if len(r.Vendor) > 0 && !attrInSlice(devPath, "vendor", r.Vendor) {
+			continue
+		}
+		if len(r.Device) > 0 && !attrInSlice(devPath, "device", r.Device) {
+			continue
+		}
+		captured := map[string]string{
+			"PciClass":  readAttr(devPath, "class"),
+			"PciVendor": readAttr(devPath, "vendor"),
+			"PciDevice": readAttr(devPath, "device"),
+		}
+		return true, captured, nil
+	}
+	return false, nil, nil
+}
+
+// readAttr reads a sysfs attribute file, returning "" if it can't be read.
+func readAttr(devPath, attr string) string {
+	raw, err := ioutil.ReadFile(filepath.Join(devPath, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(raw)), "0x")
+}
+
+// attrInSlice reads a sysfs attribute file and reports whether its (hex)
+// value, with any "0x" prefix stripped, is present in values.
+func attrInSlice(devPath, attr string, values []string) bool {
+	got := readAttr(devPath, attr)
+	if got == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(got, strings.TrimPrefix(v, "0x")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+// Rule defines an interface for matching an atomic hardware or software
+// condition against the local node.
+type Rule interface {
+	// Match returns true if the rule's condition is satisfied, along with
+	// any values captured from the match (e.g. a matched PCI device id)
+	// that feature templates may reference.
+	Match() (bool, map[string]string, error)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sysfsAllowlist restricts SysfsRule to a curated set of glob patterns so
+// that a custom rule can't be used to read arbitrary sysfs attributes.
+// Add a new entry here, scoped to the specific attribute needed, rather
+// than widening an existing one.
+var sysfsAllowlist = []string{
+	"/sys/class/net/*/device/vendor",
+	"/sys/class/net/*/device/device",
+	"/sys/class/net/*/device/subsystem_vendor",
+	"/sys/class/net/*/device/subsystem_device",
+}
+
+// SysfsRule matches a regular expression against the contents of a
+// whitelisted /sys path. Path may contain "*" glob wildcards.
+type SysfsRule struct {
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+// Match implements the Rule interface. On a match it captures the
+// attribute's value as Sysfs plus any named submatches from Value.
+func (r *SysfsRule) Match() (bool, map[string]string, error) {
+	if !sysfsPathAllowed(r.Path) {
+		return false, nil, fmt.Errorf("sysfs path %q is not in the allowlist", r.Path)
+	}
+
+	re, err := regexp.Compile(r.Value)
+	if err != nil {
+		return false, nil, err
+	}
+
+	paths, err := filepath.Glob(r.Path)
+	if err != nil {
+		return false, nil, err
+	}
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		if m := re.FindStringSubmatch(value); m != nil {
+			captured := map[string]string{"Sysfs": value}
+			for i, name := range re.SubexpNames() {
+				if i != 0 && name != "" {
+					captured[name] = m[i]
+				}
+			}
+			return true, captured, nil
+		}
+	}
+	return false, nil, nil
+}
+
+func sysfsPathAllowed(path string) bool {
+	for _, pattern := range sysfsAllowlist {
+		if ok, err := filepath.Match(pattern, path); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"io/ioutil"
+	"path/filepath"
+)
+
+const usbDevicesPath = "/sys/bus/usb/devices"
+
+// UsbIDRule matches USB devices by class, vendor and/or device id.
+type UsbIDRule struct {
+	Class  []string `json:"class,omitempty"`
+	Vendor []string `json:"vendor,omitempty"`
+	Device []string `json:"device,omitempty"`
+}
+
+// Match implements the Rule interface. On a match it captures the
+// matched device's class, vendor and product id as UsbClass, UsbVendor
+// and UsbDevice.
+func (r *UsbIDRule) Match() (bool, map[string]string, error) {
+	devDirs, err := ioutil.ReadDir(usbDevicesPath)
+	if err != nil {
+		return false, nil, err
+	}
+
+	for _, devDir := range devDirs {
+		devPath := filepath.Join(usbDevicesPath, devDir.Name())
+
+		if len(r.Class) > 0 && !attrInSlice(devPath, "bDeviceClass", r.Class) {
+			continue
+		}
+		if len(r.Vendor) > 0 && !attrInSlice(devPath, "idVendor", r.Vendor) {
+			continue
+		}
+		if len(r.Device) > 0 && !attrInSlice(devPath, "idProduct", r.Device) {
+			continue
+		}
+		captured := map[string]string{
+			"UsbClass":  readAttr(devPath, "bDeviceClass"),
+			"UsbVendor": readAttr(devPath, "idVendor"),
+			"UsbDevice": readAttr(devPath, "idProduct"),
+		}
+		return true, captured, nil
+	}
+	return false, nil, nil
+}
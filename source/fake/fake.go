@@ -18,6 +18,11 @@ package fake
 
 import (
 	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v2"
 
 	"openshift/node-feature-discovery/source"
 )
@@ -25,6 +30,16 @@ import (
 // Configuration file options
 type Config struct {
 	Labels map[string]string `json:"labels"`
+	// Scenarios, if non-empty, makes Discover() cycle through a scripted
+	// sequence of feature maps, one per invocation, instead of always
+	// returning Labels. It wraps around once exhausted.
+	Scenarios []map[string]string `json:"scenarios,omitempty"`
+	// Jitter, when set, makes Discover() randomly drop labels from its
+	// result using a seeded RNG, so tests can exercise NFD-master's
+	// convergence behavior on flaky sources.
+	Jitter bool `json:"jitter,omitempty"`
+	// Seed initializes the Jitter RNG so that test runs are reproducible.
+	Seed int64 `json:"seed,omitempty"`
 }
 
 // newDefaultConfig returns a new config with defaults values
@@ -41,10 +56,14 @@ func newDefaultConfig() *Config {
 // Source implements FeatureSource.
 type Source struct {
 	config *Config
+
+	m             sync.Mutex
+	discoverCount int
+	rng           *rand.Rand
 }
 
 // Name returns an identifier string for this feature source.
-func (s Source) Name() string { return "fake" }
+func (s *Source) Name() string { return "fake" }
 
 // NewConfig method of the FeatureSource interface
 func (s *Source) NewConfig() source.Config { return newDefaultConfig() }
@@ -62,15 +81,68 @@ func (s *Source) SetConfig(conf source.Config) {
 	}
 }
 
-// Configure method of the FeatureSource interface
-func (s Source) Configure([]byte) error { return nil }
+// Configure method of the FeatureSource interface. It accepts a YAML
+// document overriding Labels at runtime, leaving Scenarios/Jitter/Seed
+// untouched.
+func (s *Source) Configure(data []byte) error {
+	var override Config
+	if err := yaml.Unmarshal(data, &override); err != nil {
+		return fmt.Errorf("failed to parse fake source config: %w", err)
+	}
+	if override.Labels == nil {
+		return nil
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.config == nil {
+		s.config = newDefaultConfig()
+	}
+	s.config.Labels = override.Labels
+	return nil
+}
 
-// Discover returns feature names for some fake features.
-func (s Source) Discover() (source.Features, error) {
-	// Adding three fake features.
-	features := make(source.Features, len(s.config.Labels))
-	for k, v := range s.config.Labels {
-		features[k] = v
+// DiscoverCount returns the number of times Discover() has run, so tests
+// can assert how many discovery cycles happened between events.
+func (s *Source) DiscoverCount() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.discoverCount
+}
+
+// Discover returns feature names for some fake features. If Scenarios is
+// set, it cycles through the scripted feature maps instead.
+func (s *Source) Discover() (source.Features, error) {
+	s.m.Lock()
+	idx := s.discoverCount
+	s.discoverCount++
+	jitter := s.config.Jitter
+	if jitter && s.rng == nil {
+		s.rng = rand.New(rand.NewSource(s.config.Seed))
+	}
+	rng := s.rng
+
+	labels := s.config.Labels
+	if len(s.config.Scenarios) > 0 {
+		labels = s.config.Scenarios[idx%len(s.config.Scenarios)]
+	}
+	s.m.Unlock()
+
+	// Iterate in a fixed (sorted) key order so that, for a given Seed, the
+	// sequence of rng draws - and hence the set of dropped labels - is
+	// reproducible across runs despite Go's randomized map iteration.
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	features := make(source.Features, len(labels))
+	for _, k := range keys {
+		if jitter && rng.Float64() < 0.5 {
+			continue
+		}
+		features[k] = labels[k]
 	}
 
 	return features, nil
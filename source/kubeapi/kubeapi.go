@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kubeapi implements a FeatureSource that populates features from
+// the Kubernetes Node object of the node nfd-worker is running on, instead
+// of reading /proc and /sys.
+package kubeapi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"openshift/node-feature-discovery/source"
+)
+
+// Config controls which parts of the node's own Node object are surfaced
+// as features.
+type Config struct {
+	// Kubeconfig is the path to a kubeconfig file. When empty, the
+	// in-cluster config is used, falling back to $HOME/.kube/config.
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// ResyncPeriod controls how often the informer resyncs its local cache.
+	ResyncPeriod time.Duration `json:"resyncPeriod,omitempty"`
+	// LabelAllowlist is a list of regular expressions; Node labels whose
+	// key matches one of them are re-exported as NFD-managed features.
+	LabelAllowlist []string `json:"labelAllowlist,omitempty"`
+}
+
+// newDefaultConfig returns a new Config with pre-populated defaults.
+func newDefaultConfig() *Config {
+	return &Config{ResyncPeriod: time.Hour}
+}
+
+// Source implements the FeatureSource interface, backed by a cached copy
+// of the local Node object kept up to date by a SharedInformer.
+type Source struct {
+	config    *Config
+	allowlist []*regexp.Regexp
+
+	m    sync.RWMutex
+	node *corev1.Node
+}
+
+// Name returns an identifier string for this feature source.
+func (s *Source) Name() string { return "kubeapi" }
+
+// NewConfig method of the FeatureSource interface
+func (s *Source) NewConfig() source.Config { return newDefaultConfig() }
+
+// GetConfig method of the FeatureSource interface
+func (s *Source) GetConfig() source.Config { return s.config }
+
+// SetConfig method of the FeatureSource interface
+func (s *Source) SetConfig(conf source.Config) {
+	switch v := conf.(type) {
+	case *Config:
+		s.config = v
+	default:
+		klog.Fatalf("invalid config type: %T", conf)
+	}
+
+	allowlist, err := compileAllowlist(s.config.LabelAllowlist)
+	if err != nil {
+		klog.Fatalf("invalid kubeapi source config: %v", err)
+	}
+	s.allowlist = allowlist
+}
+
+// Start begins watching the local Node object and blocks until stopCh is
+// closed. It must be running (and its informer cache synced) before
+// Discover() is called.
+func (s *Source) Start(stopCh <-chan struct{}) error {
+	nodeName, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("failed to determine node name: %w", err)
+	}
+
+	restConfig, err := buildRestConfig(s.config.Kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to build kube client config: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create kube client: %w", err)
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, s.config.ResyncPeriod,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+		}),
+	)
+	informer := factory.Core().V1().Nodes().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { s.setNode(obj) },
+		UpdateFunc: func(_, obj interface{}) { s.setNode(obj) },
+		DeleteFunc: func(interface{}) { s.setNode(nil) },
+	})
+
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	klog.Infof("kubeapi feature source watching node %q", nodeName)
+	<-stopCh
+	return nil
+}
+
+func (s *Source) setNode(obj interface{}) {
+	node, _ := obj.(*corev1.Node)
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.node = node
+}
+
+func buildRestConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig != "" {
+		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	if cfg, err := rest.InClusterConfig(); err == nil {
+		return cfg, nil
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if cfg, err := clientcmd.BuildConfigFromFlags("", filepath.Join(home, ".kube", "config")); err == nil {
+			return cfg, nil
+		}
+	}
+	return nil, fmt.Errorf("no in-cluster config and no usable kubeconfig found")
+}
+
+// Discover features. It never makes a blocking API call; it only reads
+// the informer's last-cached copy of the local Node object.
+func (s *Source) Discover() (source.Features, error) {
+	s.m.RLock()
+	node := s.node
+	s.m.RUnlock()
+
+	features := source.Features{}
+	if node == nil {
+		return features, nil
+	}
+
+	features["kernelVersion"] = node.Status.NodeInfo.KernelVersion
+	features["osImage"] = node.Status.NodeInfo.OSImage
+	features["kubeletVersion"] = node.Status.NodeInfo.KubeletVersion
+	features["containerRuntimeVersion"] = node.Status.NodeInfo.ContainerRuntimeVersion
+
+	for _, taint := range node.Spec.Taints {
+		features["taint."+taint.Key] = taint.Effect
+	}
+
+	for key, value := range node.Labels {
+		if labelAllowed(key, s.allowlist) {
+			features["label."+key] = value
+		}
+	}
+
+	return features, nil
+}
+
+func compileAllowlist(patterns []string) ([]*regexp.Regexp, error) {
+	allowed := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid labelAllowlist pattern %q: %w", pattern, err)
+		}
+		allowed = append(allowed, re)
+	}
+	return allowed, nil
+}
+
+func labelAllowed(key string, allowed []*regexp.Regexp) bool {
+	for _, re := range allowed {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}